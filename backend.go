@@ -0,0 +1,69 @@
+package main
+
+import "fmt"
+
+// RepoSpec describes a single repository to mirror, independent of which
+// forge it was listed from or is being migrated to.
+type RepoSpec struct {
+	CloneURL      string
+	Name          string
+	FullName      string
+	Owner         string
+	OwnerIsOrg    bool
+	Fork          bool
+	Private       bool
+	Description   string
+	DefaultBranch string
+
+	// CloneUsername/ClonePassword are the credentials Migrate should use
+	// to authenticate the clone of CloneURL. They're filled in by runJob
+	// from source.CloneAuth once per job, not by the source itself.
+	CloneUsername string
+	ClonePassword string
+}
+
+// source lists the repositories a mirror job should consider migrating, and
+// supplies the credentials Migrate should clone spec.CloneURL with.
+type source interface {
+	ListRepos(job JobConfig) ([]RepoSpec, error)
+	CloneAuth(job JobConfig) (username, password string)
+}
+
+// destination migrates, checks for, and re-syncs repositories at the mirror
+// target. ResolveOwner is called once per distinct source owner encountered
+// and returns the destination owner name to migrate into, creating an org
+// for it first when the source owner is an org that doesn't exist yet at
+// the destination.
+type destination interface {
+	ResolveOwner(job JobConfig, ownerLogin string, ownerIsOrg, preserveOwner bool) (string, error)
+	Exists(job JobConfig, ownerName, repoName string) (bool, error)
+	Migrate(job JobConfig, ownerName string, spec RepoSpec) error
+	Sync(job JobConfig, ownerName string, spec RepoSpec) error
+}
+
+// newSource builds the source named by job.SourceType, defaulting to github.
+func newSource(job JobConfig) (source, error) {
+	switch job.SourceType {
+	case "", "github":
+		return &githubSource{}, nil
+	case "gitlab":
+		return &gitlabSource{}, nil
+	case "bitbucket":
+		return &bitbucketSource{}, nil
+	default:
+		return nil, fmt.Errorf("unknown -source %q (want github, gitlab, or bitbucket)", job.SourceType)
+	}
+}
+
+// newDestination builds the destination named by job.DestType, defaulting
+// to gogs.
+func newDestination(job JobConfig) (destination, error) {
+	switch job.DestType {
+	case "", "gogs":
+		return newGogsDestination(job), nil
+	case "gitea":
+		return newGiteaDestination(job)
+	default:
+		return nil, fmt.Errorf("unknown -dest %q (want gogs or gitea)", job.DestType)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// JobConfig describes a single source -> destination mirror pipeline: where
+// to read repositories from, where to push them, and which of them to
+// include. SourceType/DestType select which forge backend to use; the
+// remaining credential fields are only read by the backend(s) selected.
+type JobConfig struct {
+	// SourceType is "github" (default), "gitlab", or "bitbucket".
+	SourceType string `yaml:"source"`
+	// DestType is "gogs" (default) or "gitea".
+	DestType string `yaml:"dest"`
+
+	GithubUser  string `yaml:"github_user"`
+	GithubToken string `yaml:"github_token"`
+
+	GitlabURL   string `yaml:"gitlab_url"`
+	GitlabToken string `yaml:"gitlab_token"`
+	GitlabUser  string `yaml:"gitlab_user"`
+
+	BitbucketUser        string `yaml:"bitbucket_user"`
+	BitbucketAppPassword string `yaml:"bitbucket_app_password"`
+
+	GogsURL   string `yaml:"gogs_url"`
+	GogsToken string `yaml:"gogs_token"`
+	GogsUser  string `yaml:"gogs_user"`
+
+	GiteaURL   string `yaml:"gitea_url"`
+	GiteaToken string `yaml:"gitea_token"`
+	GiteaUser  string `yaml:"gitea_user"`
+
+	RepoType     string   `yaml:"repo_type"`
+	Mirror       *bool    `yaml:"mirror"`
+	IncludeForks bool     `yaml:"include_forks"`
+	Include      []string `yaml:"include"`
+	Exclude      []string `yaml:"exclude"`
+
+	// Update, when true, makes a repo that already exists at the
+	// destination safe to re-run: mirrors are force-synced, and on a
+	// Gitea destination plain repos get their description/private flag
+	// refreshed (a Gogs destination leaves plain repos unchanged), instead
+	// of MigrateRepo erroring out on conflict.
+	Update *bool `yaml:"update"`
+
+	// PreserveOwner, when true, migrates each repo into a destination
+	// org/user named after its source owner (subject to UserMap) instead
+	// of always into the destination's default user/org.
+	PreserveOwner bool `yaml:"preserve_owner"`
+	// DestUser/DestOrg override the default destination owner (GogsUser
+	// or GiteaUser) for a single-owner source.
+	DestUser string `yaml:"dest_user"`
+	DestOrg  string `yaml:"dest_org"`
+	// UserMap renames source owners to destination owners, e.g.
+	// {"user1": "userA"}.
+	UserMap map[string]string `yaml:"user_map"`
+}
+
+// Config is the top-level shape of a `-config` file: a global concurrency
+// limit plus the list of mirror jobs to run.
+type Config struct {
+	Threads int         `yaml:"threads"`
+	Jobs    []JobConfig `yaml:"jobs"`
+}
+
+// GetConfig reads and parses a YAML mirror config from path.
+func GetConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read config %s: %s", path, err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("could not parse config %s: %s", path, err)
+	}
+
+	for i := range cfg.Jobs {
+		if cfg.Jobs[i].RepoType == "" {
+			cfg.Jobs[i].RepoType = "owner"
+		}
+	}
+
+	return &cfg, nil
+}
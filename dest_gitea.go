@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	giteaapi "code.gitea.io/sdk/gitea"
+)
+
+// giteaDestination migrates repositories into a Gitea instance. Unlike Gogs,
+// Gitea's SDK exposes repo-editing, so Sync also refreshes a plain repo's
+// description/private flag.
+type giteaDestination struct {
+	client *giteaapi.Client
+}
+
+func newGiteaDestination(job JobConfig) (*giteaDestination, error) {
+	client, err := giteaapi.NewClient(job.GiteaURL, giteaapi.SetToken(job.GiteaToken))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create Gitea client: %s", err)
+	}
+
+	return &giteaDestination{client: client}, nil
+}
+
+func (d *giteaDestination) ResolveOwner(job JobConfig, ownerLogin string, ownerIsOrg, preserveOwner bool) (string, error) {
+	defaultDest := job.GiteaUser
+	if job.DestOrg != "" {
+		defaultDest = job.DestOrg
+	} else if job.DestUser != "" {
+		defaultDest = job.DestUser
+	}
+
+	if !preserveOwner && !ownerIsOrg {
+		return defaultDest, nil
+	}
+
+	mapped := ownerLogin
+	if renamed, ok := job.UserMap[ownerLogin]; ok {
+		mapped = renamed
+	}
+
+	if _, _, err := d.client.GetOrg(mapped); err == nil {
+		return mapped, nil
+	}
+	if _, _, err := d.client.GetUserInfo(mapped); err == nil {
+		return mapped, nil
+	}
+
+	if !ownerIsOrg {
+		return "", fmt.Errorf("gitea user %s does not exist", mapped)
+	}
+
+	if dryRun {
+		log.Printf("dry-run: would create missing gitea org %s", mapped)
+		return mapped, nil
+	}
+
+	log.Printf("gitea org %s does not exist, creating it", mapped)
+	if _, _, err := d.client.AdminCreateOrg(job.GiteaUser, giteaapi.CreateOrgOption{
+		Name:     mapped,
+		FullName: mapped,
+	}); err != nil {
+		return "", fmt.Errorf("couldn't create gitea org %s: %s", mapped, err)
+	}
+
+	return mapped, nil
+}
+
+func (d *giteaDestination) Exists(job JobConfig, ownerName, repoName string) (bool, error) {
+	_, _, err := d.client.GetRepo(ownerName, repoName)
+	return err == nil, nil
+}
+
+func (d *giteaDestination) Migrate(job JobConfig, ownerName string, spec RepoSpec) error {
+	mirror := true
+	if job.Mirror != nil {
+		mirror = *job.Mirror
+	}
+
+	_, _, err := d.client.MigrateRepo(giteaapi.MigrateRepoOption{
+		CloneAddr:    spec.CloneURL,
+		AuthUsername: spec.CloneUsername,
+		AuthPassword: spec.ClonePassword,
+		RepoOwner:    ownerName,
+		RepoName:     spec.Name,
+		Description:  spec.Description,
+		Private:      spec.Private,
+		Mirror:       mirror,
+	})
+	return err
+}
+
+func (d *giteaDestination) Sync(job JobConfig, ownerName string, spec RepoSpec) error {
+	existing, _, err := d.client.GetRepo(ownerName, spec.Name)
+	if err != nil {
+		return fmt.Errorf("couldn't fetch existing gitea repo %s/%s: %s", ownerName, spec.Name, err)
+	}
+
+	if existing.Mirror {
+		if _, err := d.client.MirrorSync(ownerName, spec.Name); err != nil {
+			return fmt.Errorf("mirror-sync %s/%s: %s", ownerName, spec.Name, err)
+		}
+		return nil
+	}
+
+	_, _, err = d.client.EditRepo(ownerName, spec.Name, giteaapi.EditRepoOption{
+		Description: &spec.Description,
+		Private:     &spec.Private,
+	})
+	return err
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	gogsapi "github.com/gogits/go-gogs-client"
+)
+
+const (
+	migrateRetries    = 3
+	migrateRetryDelay = 2 * time.Second
+)
+
+// gogsDestination migrates repositories into a Gogs instance. It's the
+// original, and still default, mirror destination.
+type gogsDestination struct {
+	client *gogsapi.Client
+
+	ownerIDsMu sync.Mutex
+	ownerIDs   map[string]int
+}
+
+func newGogsDestination(job JobConfig) *gogsDestination {
+	return &gogsDestination{
+		client:   gogsapi.NewClient(job.GogsURL, job.GogsToken),
+		ownerIDs: make(map[string]int),
+	}
+}
+
+func (d *gogsDestination) ResolveOwner(job JobConfig, ownerLogin string, ownerIsOrg, preserveOwner bool) (string, error) {
+	defaultDest := job.GogsUser
+	if job.DestOrg != "" {
+		defaultDest = job.DestOrg
+	} else if job.DestUser != "" {
+		defaultDest = job.DestUser
+	}
+
+	if !preserveOwner && !ownerIsOrg {
+		return defaultDest, nil
+	}
+
+	mapped := ownerLogin
+	if renamed, ok := job.UserMap[ownerLogin]; ok {
+		mapped = renamed
+	}
+
+	if _, err := d.ownerID(mapped); err == nil {
+		return mapped, nil
+	}
+
+	if !ownerIsOrg {
+		return "", fmt.Errorf("gogs user %s does not exist", mapped)
+	}
+
+	if dryRun {
+		log.Printf("dry-run: would create missing gogs org %s", mapped)
+		return mapped, nil
+	}
+
+	log.Printf("gogs org %s does not exist, creating it", mapped)
+	org, err := d.client.AdminCreateOrg(job.GogsUser, gogsapi.CreateOrgOption{
+		UserName: mapped,
+		FullName: mapped,
+	})
+	if err != nil {
+		return "", fmt.Errorf("couldn't create gogs org %s: %s", mapped, err)
+	}
+
+	d.ownerIDsMu.Lock()
+	d.ownerIDs[mapped] = int(org.ID)
+	d.ownerIDsMu.Unlock()
+	return mapped, nil
+}
+
+func (d *gogsDestination) Exists(job JobConfig, ownerName, repoName string) (bool, error) {
+	_, err := d.client.GetRepo(ownerName, repoName)
+	return err == nil, nil
+}
+
+func (d *gogsDestination) Migrate(job JobConfig, ownerName string, spec RepoSpec) error {
+	uid, err := d.ownerID(ownerName)
+	if err != nil {
+		return err
+	}
+
+	mirror := true
+	if job.Mirror != nil {
+		mirror = *job.Mirror
+	}
+
+	opts := gogsapi.MigrateRepoOption{
+		CloneAddr:    spec.CloneURL,
+		AuthUsername: spec.CloneUsername,
+		AuthPassword: spec.ClonePassword,
+
+		Private:     spec.Private,
+		UID:         uid,
+		RepoName:    spec.Name,
+		Description: spec.Description,
+		Mirror:      mirror,
+	}
+
+	if workaround1862 {
+		opts.Mirror, opts.Private = opts.Private, opts.Mirror
+	}
+
+	_, err = migrateRepoWithRetry(d.client, opts)
+	return err
+}
+
+// Sync re-syncs an already-migrated repo: a mirror is force-synced via the
+// mirror-sync endpoint; a plain repo is left unchanged (its description and
+// private flag are not refreshed). This is what makes repeated runs (e.g.
+// from cron) safe against MigrateRepo's conflict error.
+func (d *gogsDestination) Sync(job JobConfig, ownerName string, spec RepoSpec) error {
+	existing, err := d.client.GetRepo(ownerName, spec.Name)
+	if err != nil {
+		return fmt.Errorf("couldn't fetch existing gogs repo %s/%s: %s", ownerName, spec.Name, err)
+	}
+
+	if existing.Mirror {
+		return d.client.MirrorSync(ownerName, spec.Name)
+	}
+
+	// go-gogs-client has no EditRepo call, so a plain (non-mirror) repo's
+	// description/private flag can't be refreshed here; only mirrors are
+	// kept in sync by -update.
+	log.Printf("%s/%s already exists and isn't a mirror, leaving it as-is", ownerName, spec.Name)
+	return nil
+}
+
+func (d *gogsDestination) ownerID(ownerName string) (int, error) {
+	d.ownerIDsMu.Lock()
+	id, ok := d.ownerIDs[ownerName]
+	d.ownerIDsMu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	info, err := d.client.GetUserInfo(ownerName)
+	if err != nil {
+		return 0, fmt.Errorf("gogs owner %s does not exist: %s", ownerName, err)
+	}
+
+	d.ownerIDsMu.Lock()
+	d.ownerIDs[ownerName] = int(info.ID)
+	d.ownerIDsMu.Unlock()
+	return int(info.ID), nil
+}
+
+// migrateRepoWithRetry calls gogs.MigrateRepo, retrying transient failures
+// (network errors, timeouts, 5xx) with exponential backoff. Permanent
+// failures such as bad credentials or a name conflict (when not running
+// -update) fail fast instead of burning the full retry budget.
+func migrateRepoWithRetry(gogs *gogsapi.Client, opts gogsapi.MigrateRepoOption) (*gogsapi.Repository, error) {
+	var (
+		gogsRepo *gogsapi.Repository
+		err      error
+	)
+
+	delay := migrateRetryDelay
+	for attempt := 1; attempt <= migrateRetries; attempt++ {
+		gogsRepo, err = gogs.MigrateRepo(opts)
+		if err == nil {
+			return gogsRepo, nil
+		}
+
+		if attempt == migrateRetries || !isRetryableMigrateErr(err) {
+			break
+		}
+
+		log.Printf("migrate %s failed (attempt %d/%d): %s, retrying in %s", opts.RepoName, attempt, migrateRetries, err, delay)
+		time.Sleep(delay)
+		delay *= 2
+	}
+
+	return nil, err
+}
+
+// isRetryableMigrateErr reports whether err looks like a transient failure
+// worth retrying, rather than a permanent one (bad credentials, an existing
+// repo conflict without -update) that would just fail the same way again.
+// go-gogs-client's getResponse collapses every non-2xx response into an
+// error: 403/404 become the literal strings below, anything else becomes
+// whatever message the Gogs server put in the JSON error body, so a repo
+// name conflict is matched by its message text rather than a status code.
+func isRetryableMigrateErr(err error) bool {
+	msg := err.Error()
+
+	switch msg {
+	case "403 Forbidden", "404 Not Found":
+		return false
+	}
+
+	if strings.Contains(strings.ToLower(msg), "already exist") {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestIsRetryableMigrateErr(t *testing.T) {
+	tests := []struct {
+		name string
+		err  string
+		want bool
+	}{
+		{"forbidden is permanent", "403 Forbidden", false},
+		{"not found is permanent", "404 Not Found", false},
+		{"name conflict is permanent", "The repository with the same name already exists.", false},
+		{"name conflict case-insensitive", "Repo Already Exists", false},
+		{"network error is retryable", "dial tcp: connection refused", true},
+		{"server error message is retryable", "Internal Server Error", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := isRetryableMigrateErr(errString(tt.err))
+			if got != tt.want {
+				t.Errorf("isRetryableMigrateErr(%q) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// errString lets the table above spell out error text without importing
+// errors.New at every call site.
+type errString string
+
+func (e errString) Error() string { return string(e) }
@@ -4,231 +4,395 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
 	"regexp"
+	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/cheggaaa/pb"
 	"github.com/davecgh/go-spew/spew"
-	gogsapi "github.com/gogits/go-gogs-client"
-	githubapi "github.com/google/go-github/github"
-	"golang.org/x/oauth2"
 )
 
 var (
-	dryRun        bool
-	mirror        bool
-	includeForks  bool
-	repoType      string
-	excludeFilter []*regexp.Regexp
-	includeFilter []*regexp.Regexp
+	dryRun       bool
+	mirrorFlag   bool
+	includeForks bool
+	repoType     string
+	updateFlag   bool
 
 	workaround1862 bool
 
+	threads int
+
+	preserveOwner bool
+	destUser      string
+	destOrg       string
+	userMap       string
+
+	configPath string
+	sourceType string
+	destType   string
+
 	gogsURL     string
 	gogsToken   string
 	gogsUser    string
 	githubToken string
 	githubUser  string
+
+	gitlabURL   string
+	gitlabToken string
+	gitlabUser  string
+
+	bitbucketUser        string
+	bitbucketAppPassword string
+
+	giteaURL   string
+	giteaToken string
+	giteaUser  string
 )
 
 func init() {
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "%s [options] [pattern ..]\n", os.Args[0])
 		fmt.Fprintln(os.Stderr, "  pattern")
-		fmt.Fprintln(os.Stderr, "    \tPCRE regexp that full repo names (user/repo) must match.")
+		fmt.Fprintln(os.Stderr, "    \tPCRE regexp that full repo names (owner/repo) must match.")
 		fmt.Fprintln(os.Stderr, "    \tPatterns prefixed with a dash (-) must not be matched.")
+		fmt.Fprintln(os.Stderr, "    \tIgnored when -config is used; put include/exclude in the config file instead.")
 		flag.PrintDefaults()
 	}
 
+	flag.StringVar(&configPath, "config", "", "Path to a YAML config file describing one or more mirror jobs. Flags below act as overrides/defaults for single-job usage.")
+	flag.IntVar(&threads, "threads", 4, "Number of repositories to migrate concurrently")
+
+	flag.StringVar(&sourceType, "source", "github", "Where to read repositories from: github | gitlab | bitbucket")
+	flag.StringVar(&destType, "dest", "gogs", "Where to migrate repositories to: gogs | gitea")
+
 	flag.BoolVar(&workaround1862, "workaround-1862", false, `Swap the "private" and "mirror" Gogs API fields (workaround for https://github.com/gogits/gogs/pull/1862)`)
 
 	flag.BoolVar(&dryRun, "dry-run", false, "Only print information about the migrations that would be performed.")
-	flag.BoolVar(&mirror, "mirror", true, "Create the Gogs repositories as mirrors")
+	flag.BoolVar(&mirrorFlag, "mirror", true, "Create the destination repositories as mirrors")
 	flag.BoolVar(&includeForks, "include-forks", false, "Include forks")
-	flag.StringVar(&repoType, "repo-type", "owner", "all | owner | public | private | member")
+	flag.StringVar(&repoType, "repo-type", "owner", "all | owner | public | private | member | starred (GitHub source only: mirror -github-user's starred repos)")
+	flag.BoolVar(&updateFlag, "update", false, "Safe to re-run: force-sync existing mirrors (and, with -dest gitea, update existing plain repos) instead of erroring on conflict")
+
+	flag.StringVar(&githubUser, "github-user", "", "GitHub source user")
+	flag.StringVar(&githubToken, "github-token", "", "GitHub API token")
+
+	flag.StringVar(&gitlabURL, "gitlab-url", "", "URL of the GitLab instance (defaults to gitlab.com)")
+	flag.StringVar(&gitlabToken, "gitlab-token", "", "GitLab API token")
+	flag.StringVar(&gitlabUser, "gitlab-user", "", "GitLab source user")
+
+	flag.StringVar(&bitbucketUser, "bitbucket-user", "", "Bitbucket source account")
+	flag.StringVar(&bitbucketAppPassword, "bitbucket-app-password", "", "Bitbucket app password")
 
 	flag.StringVar(&gogsURL, "gogs-url", "", "URL of the target Gogs instance")
 	flag.StringVar(&gogsToken, "gogs-token", "", "Gogs API token")
 	flag.StringVar(&gogsUser, "gogs-user", "", "Gogs target user")
-	flag.StringVar(&githubToken, "github-token", "", "GitHub API token")
-	flag.StringVar(&githubUser, "github-user", "", "GitHub source user")
+
+	flag.StringVar(&giteaURL, "gitea-url", "", "URL of the target Gitea instance")
+	flag.StringVar(&giteaToken, "gitea-token", "", "Gitea API token")
+	flag.StringVar(&giteaUser, "gitea-user", "", "Gitea target user")
+
+	flag.BoolVar(&preserveOwner, "preserve-owner", false, "Migrate each repo into a destination org/user named after its source owner, creating the org if needed")
+	flag.StringVar(&destUser, "dest-user", "", "Override the destination user (defaults to -gogs-user/-gitea-user)")
+	flag.StringVar(&destOrg, "dest-org", "", "Override the destination org (defaults to -gogs-user/-gitea-user)")
+	flag.StringVar(&userMap, "user-map", "", "Comma-separated source=dest owner renames, e.g. user1=userA,user2=userB")
 }
 
-var (
-	gogs   *gogsapi.Client
-	github *githubapi.Client
-)
+// parseUserMap parses a "user1=userA,user2=userB" string into a lookup map.
+func parseUserMap(s string) (map[string]string, error) {
+	if s == "" {
+		return nil, nil
+	}
 
-func main() {
-	flag.Parse()
-	if repoType == "" || gogsURL == "" || gogsToken == "" || gogsUser == "" || githubToken == "" {
+	m := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("invalid -user-map entry %q, expected source=dest", pair)
+		}
+		m[kv[0]] = kv[1]
+	}
+
+	return m, nil
+}
+
+// jobsFromFlags builds a single-job Config from the legacy command-line
+// flags, so `-config` and flag-only invocations share the same execution
+// path.
+func jobsFromFlags() (*Config, error) {
+	if repoType == "" {
 		flag.Usage()
 		os.Exit(2)
 	}
 
+	switch sourceType {
+	case "", "github":
+		if githubToken == "" {
+			flag.Usage()
+			os.Exit(2)
+		}
+	case "gitlab":
+		if gitlabToken == "" || gitlabUser == "" {
+			flag.Usage()
+			os.Exit(2)
+		}
+	case "bitbucket":
+		if bitbucketUser == "" || bitbucketAppPassword == "" {
+			flag.Usage()
+			os.Exit(2)
+		}
+	}
+
+	switch destType {
+	case "", "gogs":
+		if gogsURL == "" || gogsToken == "" || gogsUser == "" {
+			flag.Usage()
+			os.Exit(2)
+		}
+	case "gitea":
+		if giteaURL == "" || giteaToken == "" || giteaUser == "" {
+			flag.Usage()
+			os.Exit(2)
+		}
+	}
+
+	parsedUserMap, err := parseUserMap(userMap)
+	if err != nil {
+		return nil, err
+	}
+
+	job := JobConfig{
+		SourceType: sourceType,
+		DestType:   destType,
+
+		GithubUser:  githubUser,
+		GithubToken: githubToken,
+
+		GitlabURL:   gitlabURL,
+		GitlabToken: gitlabToken,
+		GitlabUser:  gitlabUser,
+
+		BitbucketUser:        bitbucketUser,
+		BitbucketAppPassword: bitbucketAppPassword,
+
+		GogsURL:   gogsURL,
+		GogsToken: gogsToken,
+		GogsUser:  gogsUser,
+
+		GiteaURL:   giteaURL,
+		GiteaToken: giteaToken,
+		GiteaUser:  giteaUser,
+
+		RepoType:      repoType,
+		Mirror:        &mirrorFlag,
+		IncludeForks:  includeForks,
+		Update:        &updateFlag,
+		PreserveOwner: preserveOwner,
+		DestUser:      destUser,
+		DestOrg:       destOrg,
+		UserMap:       parsedUserMap,
+	}
+
 	for _, filter := range flag.Args() {
-		first := filter[0:1]
-		if first == "-" {
-			filter = filter[1:]
+		if filter[0:1] == "-" {
+			job.Exclude = append(job.Exclude, filter[1:])
 		} else {
-			first = ""
+			job.Include = append(job.Include, filter)
 		}
+	}
 
-		re, err := regexp.Compile(filter)
+	return &Config{Jobs: []JobConfig{job}}, nil
+}
+
+func main() {
+	flag.Parse()
+
+	var (
+		cfg *Config
+		err error
+	)
+	if configPath != "" {
+		cfg, err = GetConfig(configPath)
 		if err != nil {
-			log.Fatalf("could not parse %s%s: %s", first, filter, err)
+			log.Fatal(err)
 		}
-
-		if first == "-" {
-			excludeFilter = append(excludeFilter, re)
-		} else {
-			includeFilter = append(includeFilter, re)
+	} else {
+		cfg, err = jobsFromFlags()
+		if err != nil {
+			log.Fatal(err)
 		}
 	}
 
-	gogs = gogsapi.NewClient(gogsURL, gogsToken)
-	var githubHttp *http.Client
-	if githubToken != "" {
-		githubHttp = oauth2.NewClient(oauth2.NoContext,
-			oauth2.StaticTokenSource(&oauth2.Token{AccessToken: githubToken}))
+	jobThreads := threads
+	if cfg.Threads > 0 {
+		jobThreads = cfg.Threads
 	}
-	github = githubapi.NewClient(githubHttp)
 
-	githubTokenUserData, _, err := github.Users.Get("")
-	if err != nil {
-		log.Fatalf("couldn't fetch GitHub user: %s", err)
+	failed := false
+	for _, job := range cfg.Jobs {
+		if err := runJob(job, jobThreads); err != nil {
+			log.Printf("job %s -> %s failed: %s", job.SourceType, job.DestType, err)
+			failed = true
+		}
 	}
-	githubTokenUser := *githubTokenUserData.Login
 
-	githubUserData, _, err := github.Users.Get(githubUser)
-	if err != nil {
-		log.Fatalf("couldn't fetch GitHub user: %s", err)
+	if failed {
+		os.Exit(1)
 	}
-	githubUserIsOrg := githubUserData.Type != nil && *githubUserData.Type == "Organization"
+}
+
+// mirrorJob is one repo queued up for a worker to migrate or sync.
+type mirrorJob struct {
+	spec      RepoSpec
+	ownerName string
+}
 
-	listOpts := githubapi.ListOptions{
-		Page:    0,
-		PerPage: 100,
+func runJob(job JobConfig, threads int) error {
+	update := false
+	if job.Update != nil {
+		update = *job.Update
 	}
 
-	var repos []githubapi.Repository
+	src, err := newSource(job)
+	if err != nil {
+		return err
+	}
+	dst, err := newDestination(job)
+	if err != nil {
+		return err
+	}
 
-	for {
-		var (
-			pageRepos []githubapi.Repository
-			resp      *githubapi.Response
-			err       error
-		)
-		if githubUserIsOrg {
-			pageRepos, resp, err = github.Repositories.ListByOrg(githubUser, &githubapi.RepositoryListByOrgOptions{
-				Type:        repoType,
-				ListOptions: listOpts,
-			})
-		} else {
-			pageRepos, resp, err = github.Repositories.List(githubUser, &githubapi.RepositoryListOptions{
-				Type:        repoType,
-				ListOptions: listOpts,
-			})
+	var excludeFilter []*regexp.Regexp
+	var includeFilter []*regexp.Regexp
+	for _, filter := range job.Exclude {
+		re, err := regexp.Compile(filter)
+		if err != nil {
+			return fmt.Errorf("could not parse -%s: %s", filter, err)
 		}
+		excludeFilter = append(excludeFilter, re)
+	}
+	for _, filter := range job.Include {
+		re, err := regexp.Compile(filter)
 		if err != nil {
-			log.Fatalf("couldn't fetch GitHub repository list: %s", err)
+			return fmt.Errorf("could not parse %s: %s", filter, err)
 		}
+		includeFilter = append(includeFilter, re)
+	}
 
-	repoLoop:
-		for _, repo := range pageRepos {
-			if !includeForks && *repo.Fork {
-				continue
-			}
+	repos, err := src.ListRepos(job)
+	if err != nil {
+		return err
+	}
+	cloneUsername, clonePassword := src.CloneAuth(job)
 
-			if includeFilter != nil {
-				for _, re := range includeFilter {
-					if !re.Match([]byte(*repo.FullName)) {
-						continue repoLoop
-					}
+	var filtered []RepoSpec
+repoLoop:
+	for _, repo := range repos {
+		if !job.IncludeForks && repo.Fork {
+			continue
+		}
+
+		if includeFilter != nil {
+			for _, re := range includeFilter {
+				if !re.MatchString(repo.FullName) {
+					continue repoLoop
 				}
 			}
+		}
 
-			if excludeFilter != nil {
-				for _, re := range excludeFilter {
-					if re.Match([]byte(*repo.FullName)) {
-						continue repoLoop
-					}
+		if excludeFilter != nil {
+			for _, re := range excludeFilter {
+				if re.MatchString(repo.FullName) {
+					continue repoLoop
 				}
 			}
-
-			fmt.Println(*repo.FullName)
-			repos = append(repos, repo)
 		}
 
-		listOpts.Page = resp.NextPage
-		if resp.NextPage == 0 {
-			break
-		}
-	}
+		repo.CloneUsername = cloneUsername
+		repo.ClonePassword = clonePassword
 
-	gogsUserData, err := gogs.GetUserInfo(gogsUser)
-	if err != nil {
-		log.Fatalf("couldn't fetch Gogs user: %s", err)
+		fmt.Println(repo.FullName)
+		filtered = append(filtered, repo)
 	}
-	gogsUserID := int(gogsUserData.ID)
 
-	log.Printf("preparing to copy %d repos", len(repos))
+	log.Printf("preparing to copy %d repos using %d worker(s)", len(filtered), threads)
 	var (
 		bar *pb.ProgressBar
 		wg  sync.WaitGroup
 	)
 
 	if !dryRun {
-		bar = pb.StartNew(len(repos))
+		bar = pb.StartNew(len(filtered))
 	}
 
-	gogsRepos := make([]*gogsapi.Repository, len(repos))
-	for i, repo := range repos {
-		var repoDescription string
-		if repo.Description != nil {
-			repoDescription = *repo.Description
-		}
-
-		opts := gogsapi.MigrateRepoOption{
-			CloneAddr:    *repo.CloneURL,
-			AuthUsername: githubTokenUser,
-
-			Private:     *repo.Private,
-			UID:         gogsUserID,
-			RepoName:    *repo.Name,
-			Description: repoDescription,
-			Mirror:      mirror,
-		}
-
-		if dryRun {
-			spew.Dump(opts)
-			continue
-		}
-
-		opts.AuthPassword = githubToken
-		if workaround1862 {
-			opts.Mirror, opts.Private = opts.Private, opts.Mirror
-		}
+	jobs := make(chan mirrorJob, len(filtered))
+	var failures int32
 
+	for w := 0; w < threads; w++ {
 		wg.Add(1)
-		i := i
 		go func() {
 			defer wg.Done()
-			defer bar.Increment()
+			for mj := range jobs {
+				err := syncOrMigrate(dst, job, update, mj.ownerName, mj.spec)
+				if bar != nil {
+					bar.Increment()
+				}
+				if err != nil {
+					log.Printf("failed to migrate repo %s: %s", mj.spec.FullName, err)
+					atomic.AddInt32(&failures, 1)
+				}
+			}
+		}()
+	}
 
-			gogsRepo, err := gogs.MigrateRepo(opts)
+	ownerNames := make(map[string]string)
+	for _, repo := range filtered {
+		ownerName, ok := ownerNames[repo.Owner]
+		if !ok {
+			ownerName, err = dst.ResolveOwner(job, repo.Owner, repo.OwnerIsOrg, job.PreserveOwner)
 			if err != nil {
-				log.Printf("failed to migrate repo %s: %s", *repo.FullName, err)
-				return
+				log.Printf("skipping %s: %s", repo.FullName, err)
+				atomic.AddInt32(&failures, 1)
+				continue
 			}
+			ownerNames[repo.Owner] = ownerName
+		}
 
-			gogsRepos[i] = gogsRepo
-		}()
+		if dryRun {
+			spew.Dump(repo)
+			continue
+		}
+
+		jobs <- mirrorJob{spec: repo, ownerName: ownerName}
 	}
+	close(jobs)
 	wg.Wait()
 	if bar != nil {
 		bar.Update()
 	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d of %d repos failed to migrate", failures, len(filtered))
+	}
+
+	return nil
+}
+
+// syncOrMigrate migrates spec as today, unless update is set and the repo
+// already exists at ownerName, in which case it's synced in place instead of
+// erroring out on MigrateRepo's conflict.
+func syncOrMigrate(dst destination, job JobConfig, update bool, ownerName string, spec RepoSpec) error {
+	if update {
+		exists, err := dst.Exists(job, ownerName, spec.Name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return dst.Sync(job, ownerName, spec)
+		}
+	}
+
+	return dst.Migrate(job, ownerName, spec)
 }
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseUserMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"empty string", "", nil, false},
+		{"single pair", "user1=userA", map[string]string{"user1": "userA"}, false},
+		{"multiple pairs", "user1=userA,user2=userB", map[string]string{"user1": "userA", "user2": "userB"}, false},
+		{"missing equals", "user1", nil, true},
+		{"empty source", "=userA", nil, true},
+		{"empty dest", "user1=", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseUserMap(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseUserMap(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseUserMap(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+
+	bitbucketapi "github.com/ktrysmt/go-bitbucket"
+)
+
+// bitbucketSource lists the repositories owned by a Bitbucket account,
+// authenticating with an app password rather than OAuth.
+type bitbucketSource struct{}
+
+func (s *bitbucketSource) ListRepos(job JobConfig) ([]RepoSpec, error) {
+	bb := bitbucketapi.NewBasicAuth(job.BitbucketUser, job.BitbucketAppPassword)
+
+	res, err := bb.Repositories.ListForAccount(&bitbucketapi.RepositoriesOptions{Owner: job.BitbucketUser})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't fetch Bitbucket repository list: %s", err)
+	}
+
+	var specs []RepoSpec
+	for _, repo := range res.Items {
+		specs = append(specs, RepoSpec{
+			CloneURL:      cloneURLFromLinks(repo.Links),
+			Name:          repo.Slug,
+			FullName:      repo.Full_name,
+			Owner:         job.BitbucketUser,
+			Description:   repo.Description,
+			Private:       repo.Is_private,
+			DefaultBranch: repo.Mainbranch.Name,
+		})
+	}
+
+	return specs, nil
+}
+
+func (s *bitbucketSource) CloneAuth(job JobConfig) (username, password string) {
+	return job.BitbucketUser, job.BitbucketAppPassword
+}
+
+// cloneURLFromLinks pulls the "https" clone href out of a Bitbucket
+// Repository's Links field. go-bitbucket decodes links as a bare
+// map[string]interface{} rather than a typed struct, so "clone" is a
+// []interface{} of {"name", "href"} maps that has to be asserted by hand.
+func cloneURLFromLinks(links map[string]interface{}) string {
+	cloneLinks, ok := links["clone"].([]interface{})
+	if !ok {
+		return ""
+	}
+
+	for _, l := range cloneLinks {
+		link, ok := l.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if name, _ := link["name"].(string); name == "https" {
+			href, _ := link["href"].(string)
+			return href
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestCloneURLFromLinks(t *testing.T) {
+	tests := []struct {
+		name  string
+		links map[string]interface{}
+		want  string
+	}{
+		{
+			name: "https link present",
+			links: map[string]interface{}{
+				"clone": []interface{}{
+					map[string]interface{}{"name": "ssh", "href": "git@bitbucket.org:owner/repo.git"},
+					map[string]interface{}{"name": "https", "href": "https://bitbucket.org/owner/repo.git"},
+				},
+			},
+			want: "https://bitbucket.org/owner/repo.git",
+		},
+		{
+			name:  "no clone key",
+			links: map[string]interface{}{},
+			want:  "",
+		},
+		{
+			name: "no https entry",
+			links: map[string]interface{}{
+				"clone": []interface{}{
+					map[string]interface{}{"name": "ssh", "href": "git@bitbucket.org:owner/repo.git"},
+				},
+			},
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cloneURLFromLinks(tt.links); got != tt.want {
+				t.Errorf("cloneURLFromLinks(%v) = %q, want %q", tt.links, got, tt.want)
+			}
+		})
+	}
+}
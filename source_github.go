@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	githubapi "github.com/google/go-github/github"
+	"golang.org/x/oauth2"
+)
+
+// githubSource lists repositories owned by a GitHub user or organization.
+// It's the original, and still default, mirror source.
+type githubSource struct {
+	tokenUser string
+}
+
+func (s *githubSource) ListRepos(job JobConfig) ([]RepoSpec, error) {
+	ctx := context.Background()
+
+	var githubHttp *http.Client
+	if job.GithubToken != "" {
+		githubHttp = oauth2.NewClient(ctx,
+			oauth2.StaticTokenSource(&oauth2.Token{AccessToken: job.GithubToken}))
+	}
+	github := githubapi.NewClient(githubHttp)
+
+	tokenUserData, _, err := github.Users.Get(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't fetch GitHub user: %s", err)
+	}
+	s.tokenUser = *tokenUserData.Login
+
+	if job.RepoType == "starred" {
+		return s.listStarred(ctx, github, job)
+	}
+
+	githubUserData, _, err := github.Users.Get(ctx, job.GithubUser)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't fetch GitHub user: %s", err)
+	}
+	githubUserIsOrg := githubUserData.Type != nil && *githubUserData.Type == "Organization"
+
+	listOpts := githubapi.ListOptions{
+		Page:    0,
+		PerPage: 100,
+	}
+
+	var specs []RepoSpec
+	for {
+		var (
+			pageRepos []*githubapi.Repository
+			resp      *githubapi.Response
+			err       error
+		)
+		if githubUserIsOrg {
+			pageRepos, resp, err = github.Repositories.ListByOrg(ctx, job.GithubUser, &githubapi.RepositoryListByOrgOptions{
+				Type:        job.RepoType,
+				ListOptions: listOpts,
+			})
+		} else {
+			pageRepos, resp, err = github.Repositories.List(ctx, job.GithubUser, &githubapi.RepositoryListOptions{
+				Type:        job.RepoType,
+				ListOptions: listOpts,
+			})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("couldn't fetch GitHub repository list: %s", err)
+		}
+
+		for _, repo := range pageRepos {
+			specs = append(specs, repoSpecFromGithub(*repo, job.GithubUser, githubUserIsOrg))
+		}
+
+		listOpts.Page = resp.NextPage
+		if resp.NextPage == 0 {
+			break
+		}
+	}
+
+	return specs, nil
+}
+
+// listStarred fetches the repos job.GithubUser has starred (or, if
+// GithubUser is empty, the token owner's), for -repo-type starred. Unlike
+// the owner/org listing, each starred repo keeps its own owner rather than
+// defaulting to GithubUser, since starred repos belong to whoever owns them.
+func (s *githubSource) listStarred(ctx context.Context, github *githubapi.Client, job JobConfig) ([]RepoSpec, error) {
+	listOpts := githubapi.ActivityListStarredOptions{
+		ListOptions: githubapi.ListOptions{PerPage: 100},
+	}
+
+	var specs []RepoSpec
+	for {
+		starred, resp, err := github.Activity.ListStarred(ctx, job.GithubUser, &listOpts)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't fetch starred GitHub repos: %s", err)
+		}
+
+		for _, repo := range starred {
+			specs = append(specs, repoSpecFromGithub(*repo.Repository, job.GithubUser, false))
+		}
+
+		listOpts.Page = resp.NextPage
+		if resp.NextPage == 0 {
+			break
+		}
+	}
+
+	return specs, nil
+}
+
+// CloneAuth clones as the token's own user, matching GitHub's rule that an
+// OAuth token must be presented alongside the login it belongs to.
+func (s *githubSource) CloneAuth(job JobConfig) (username, password string) {
+	return s.tokenUser, job.GithubToken
+}
+
+func repoSpecFromGithub(repo githubapi.Repository, defaultOwner string, defaultOwnerIsOrg bool) RepoSpec {
+	var description string
+	if repo.Description != nil {
+		description = *repo.Description
+	}
+
+	var defaultBranch string
+	if repo.DefaultBranch != nil {
+		defaultBranch = *repo.DefaultBranch
+	}
+
+	owner := defaultOwner
+	ownerIsOrg := defaultOwnerIsOrg
+	if repo.Owner != nil && repo.Owner.Login != nil {
+		owner = *repo.Owner.Login
+		ownerIsOrg = repo.Owner.Type != nil && *repo.Owner.Type == "Organization"
+	}
+
+	return RepoSpec{
+		CloneURL:      *repo.CloneURL,
+		Name:          *repo.Name,
+		FullName:      *repo.FullName,
+		Owner:         owner,
+		OwnerIsOrg:    ownerIsOrg,
+		Fork:          repo.Fork != nil && *repo.Fork,
+		Private:       repo.Private != nil && *repo.Private,
+		Description:   description,
+		DefaultBranch: defaultBranch,
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+
+	gitlabapi "github.com/xanzy/go-gitlab"
+)
+
+// gitlabSource lists the projects owned by a GitLab user; a group's projects
+// are not listed.
+type gitlabSource struct{}
+
+func (s *gitlabSource) ListRepos(job JobConfig) ([]RepoSpec, error) {
+	var clientOpts []gitlabapi.ClientOptionFunc
+	if job.GitlabURL != "" {
+		clientOpts = append(clientOpts, gitlabapi.WithBaseURL(job.GitlabURL))
+	}
+	git, err := gitlabapi.NewClient(job.GitlabToken, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create GitLab client: %s", err)
+	}
+
+	opts := &gitlabapi.ListProjectsOptions{
+		ListOptions: gitlabapi.ListOptions{Page: 1, PerPage: 100},
+	}
+
+	var specs []RepoSpec
+	for {
+		projects, resp, err := git.Projects.ListUserProjects(job.GitlabUser, opts)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't fetch GitLab project list: %s", err)
+		}
+
+		for _, p := range projects {
+			specs = append(specs, repoSpecFromGitlab(p, job.GitlabUser))
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return specs, nil
+}
+
+// CloneAuth uses GitLab's "oauth2" clone-username convention: any personal
+// or project access token is accepted as the password alongside that fixed
+// username.
+func (s *gitlabSource) CloneAuth(job JobConfig) (username, password string) {
+	return "oauth2", job.GitlabToken
+}
+
+func repoSpecFromGitlab(p *gitlabapi.Project, defaultOwner string) RepoSpec {
+	owner := defaultOwner
+	var ownerIsOrg bool
+	if p.Namespace != nil && p.Namespace.Path != "" {
+		owner = p.Namespace.Path
+		ownerIsOrg = p.Namespace.Kind == "group"
+	}
+
+	return RepoSpec{
+		CloneURL:      p.HTTPURLToRepo,
+		Name:          p.Path,
+		FullName:      p.PathWithNamespace,
+		Owner:         owner,
+		OwnerIsOrg:    ownerIsOrg,
+		Fork:          p.ForkedFromProject != nil,
+		Private:       p.Visibility == gitlabapi.PrivateVisibility,
+		Description:   p.Description,
+		DefaultBranch: p.DefaultBranch,
+	}
+}